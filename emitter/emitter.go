@@ -0,0 +1,217 @@
+// Package emitter renders a graph of named struct types into Go source
+// that includes, alongside each struct's field declarations, a hand-rolled
+// MarshalJSON and UnmarshalJSON method pair. The marshaler writes field
+// names and bool and float64 values directly to a bytes.Buffer instead of
+// going through encoding/json's reflection-based encoder; the unmarshaler
+// decodes into a map[string]json.RawMessage and dispatches on the field
+// name with a switch, rather than letting encoding/json match struct tags
+// by reflection. String fields, and every other field type (nested,
+// slice, int64, ...), still delegate to json.Marshal for the value itself,
+// since reimplementing a full JSON codec is out of scope here.
+package emitter
+
+import (
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+)
+
+// Field describes one struct field to emit.
+type Field struct {
+	GoName    string
+	JSONKey   string
+	GoType    string
+	Omitempty bool
+}
+
+// Type describes one named struct type to emit.
+type Type struct {
+	Name   string
+	Fields []Field
+}
+
+// Options configures the generated (un)marshal methods.
+type Options struct {
+	// DisallowUnknown makes UnmarshalJSON return an error for JSON object
+	// keys that don't match any field, instead of silently ignoring them.
+	DisallowUnknown bool
+	// ExtraImports are additional import paths (e.g. "time", "math/big")
+	// needed by field types beyond the ones Generate always imports for
+	// itself.
+	ExtraImports []string
+}
+
+// Generate renders a complete Go source file declaring every type in types,
+// along with MarshalJSON/UnmarshalJSON methods for each.
+func Generate(types []Type, pkgName string, opts Options) ([]byte, error) {
+	var body strings.Builder
+	fmt.Fprintf(&body, "package %s\n\n", pkgName)
+
+	imports := map[string]bool{"bytes": true, "encoding/json": true, "fmt": true, "strconv": true}
+	for _, imp := range opts.ExtraImports {
+		imports[imp] = true
+	}
+	importList := make([]string, 0, len(imports))
+	for imp := range imports {
+		importList = append(importList, imp)
+	}
+	sort.Strings(importList)
+	body.WriteString("import (\n")
+	for _, imp := range importList {
+		fmt.Fprintf(&body, "\t%q\n", imp)
+	}
+	body.WriteString(")\n\n")
+
+	for _, t := range types {
+		body.WriteString(renderStruct(t))
+		body.WriteString("\n\n")
+		body.WriteString(renderMarshal(t))
+		body.WriteString("\n\n")
+		body.WriteString(renderUnmarshal(t, opts))
+		body.WriteString("\n\n")
+	}
+
+	formatted, err := format.Source([]byte(body.String()))
+	if err != nil {
+		return nil, fmt.Errorf("error formatting: %s, was formatting\n%s", err, body.String())
+	}
+	return formatted, nil
+}
+
+func renderStruct(t Type) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "type %s struct {", t.Name)
+	for _, f := range t.Fields {
+		tag := f.JSONKey
+		if f.Omitempty {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(&b, "\n\t%s %s `json:%q`", f.GoName, f.GoType, tag)
+	}
+	b.WriteString("\n}")
+	return b.String()
+}
+
+// scalarKind classifies the handful of Go types that the marshaler handles
+// specially: bool and float64 values are written directly with strconv,
+// and string values go through json.Marshal but get their own omitempty
+// check.
+type scalarKind int
+
+const (
+	kindOther scalarKind = iota
+	kindString
+	kindBool
+	kindFloat
+)
+
+func classify(goType string) (kind scalarKind, pointer bool) {
+	t := goType
+	if strings.HasPrefix(t, "*") {
+		pointer = true
+		t = t[1:]
+	}
+	switch t {
+	case "string":
+		return kindString, pointer
+	case "bool":
+		return kindBool, pointer
+	case "float64":
+		return kindFloat, pointer
+	}
+	return kindOther, pointer
+}
+
+func renderMarshal(t Type) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "func (v *%s) MarshalJSON() ([]byte, error) {\n", t.Name)
+	b.WriteString("\tvar buf bytes.Buffer\n")
+	b.WriteString("\tbuf.WriteByte('{')\n")
+	b.WriteString("\tfirst := true\n")
+	b.WriteString("\twriteKey := func(key string) {\n")
+	b.WriteString("\t\tif !first {\n\t\t\tbuf.WriteByte(',')\n\t\t}\n")
+	b.WriteString("\t\tfirst = false\n")
+	b.WriteString("\t\tbuf.WriteString(strconv.Quote(key))\n")
+	b.WriteString("\t\tbuf.WriteByte(':')\n")
+	b.WriteString("\t}\n")
+
+	for _, f := range t.Fields {
+		kind, pointer := classify(f.GoType)
+		value := "v." + f.GoName
+		if pointer {
+			value = "*" + value
+		}
+
+		var writeValue string
+		switch kind {
+		case kindString:
+			// strconv.Quote produces Go string-literal syntax, not JSON -
+			// it escapes control bytes as \xNN, which isn't a legal JSON
+			// escape. Go through json.Marshal instead so the output is
+			// always valid JSON.
+			writeValue = fmt.Sprintf("if b, err := json.Marshal(%s); err != nil {\n\t\t\treturn nil, err\n\t\t} else {\n\t\t\tbuf.Write(b)\n\t\t}", value)
+		case kindBool:
+			writeValue = fmt.Sprintf("buf.WriteString(strconv.FormatBool(%s))", value)
+		case kindFloat:
+			writeValue = fmt.Sprintf("buf.WriteString(strconv.FormatFloat(%s, 'g', -1, 64))", value)
+		default:
+			writeValue = fmt.Sprintf("if b, err := json.Marshal(v.%s); err != nil {\n\t\t\treturn nil, err\n\t\t} else {\n\t\t\tbuf.Write(b)\n\t\t}", f.GoName)
+		}
+
+		switch {
+		case pointer:
+			fmt.Fprintf(&b, "\tif v.%s != nil {\n\t\twriteKey(%q)\n\t\t%s\n\t}\n", f.GoName, f.JSONKey, writeValue)
+		case f.Omitempty && kind == kindString:
+			fmt.Fprintf(&b, "\tif v.%s != \"\" {\n\t\twriteKey(%q)\n\t\t%s\n\t}\n", f.GoName, f.JSONKey, writeValue)
+		case f.Omitempty && kind == kindFloat:
+			fmt.Fprintf(&b, "\tif v.%s != 0 {\n\t\twriteKey(%q)\n\t\t%s\n\t}\n", f.GoName, f.JSONKey, writeValue)
+		case f.Omitempty && kind == kindBool:
+			fmt.Fprintf(&b, "\tif v.%s {\n\t\twriteKey(%q)\n\t\t%s\n\t}\n", f.GoName, f.JSONKey, writeValue)
+		case f.Omitempty && strings.HasPrefix(f.GoType, "[]"):
+			fmt.Fprintf(&b, "\tif len(v.%s) > 0 {\n\t\twriteKey(%q)\n\t\t%s\n\t}\n", f.GoName, f.JSONKey, writeValue)
+		default:
+			fmt.Fprintf(&b, "\twriteKey(%q)\n\t%s\n", f.JSONKey, writeValue)
+		}
+	}
+
+	b.WriteString("\tbuf.WriteByte('}')\n")
+	b.WriteString("\treturn buf.Bytes(), nil\n")
+	b.WriteString("}")
+	return b.String()
+}
+
+func renderUnmarshal(t Type, opts Options) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "func (v *%s) UnmarshalJSON(data []byte) error {\n", t.Name)
+	b.WriteString("\tvar raw map[string]json.RawMessage\n")
+	b.WriteString("\tif err := json.Unmarshal(data, &raw); err != nil {\n\t\treturn err\n\t}\n")
+	b.WriteString("\tfor key, msg := range raw {\n")
+	b.WriteString("\t\tswitch key {\n")
+
+	keys := make([]string, len(t.Fields))
+	byKey := make(map[string]Field, len(t.Fields))
+	for i, f := range t.Fields {
+		keys[i] = f.JSONKey
+		byKey[f.JSONKey] = f
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		f := byKey[key]
+		fmt.Fprintf(&b, "\t\tcase %q:\n", f.JSONKey)
+		fmt.Fprintf(&b, "\t\t\tif err := json.Unmarshal(msg, &v.%s); err != nil {\n\t\t\t\treturn fmt.Errorf(\"field %%q: %%w\", key, err)\n\t\t\t}\n", f.GoName)
+	}
+
+	b.WriteString("\t\tdefault:\n")
+	if opts.DisallowUnknown {
+		fmt.Fprintf(&b, "\t\t\treturn fmt.Errorf(\"unknown field %%q for %s\", key)\n", t.Name)
+	} else {
+		b.WriteString("\t\t\t// unknown field, ignored\n")
+	}
+	b.WriteString("\t\t}\n")
+	b.WriteString("\t}\n")
+	b.WriteString("\treturn nil\n")
+	b.WriteString("}")
+	return b.String()
+}
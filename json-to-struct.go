@@ -47,12 +47,18 @@ import (
 	"fmt"
 	"go/format"
 	"io"
+	"math/big"
 	"os"
-	"reflect"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 	"unicode"
 	"unicode/utf8"
+
+	"github.com/wyc/gojson/emitter"
+	"github.com/wyc/gojson/schema"
 )
 
 type Style string
@@ -82,34 +88,238 @@ var (
 			StyleUnderscores, StyleCamelCase, StyleNone))
 	bsonStyle = StyleNone
 	bsonExtra = flag.String("bson-extra", "", "any extras to add to the tag")
+	inputMode = flag.String("input", "example",
+		`the kind of input on stdin: "example" (a sample JSON document) or "schema" (a JSON Schema document)`)
+	uuidType = flag.String("uuid-type", "string",
+		`the Go type to use for UUID strings (JSON Schema string properties with format "uuid", and UUID-shaped strings in example input), e.g. "github.com/google/uuid.UUID"`)
+	samplesFlag = flag.String("samples", "",
+		"comma-separated list of files containing JSON samples to merge; "+
+			"if empty, samples are read as a stream of JSON documents from stdin (e.g. NDJSON)")
+	conflictPolicy = flag.String("conflict-policy", "interface",
+		`how to resolve a field whose samples disagree on type: "interface" (interface{}) or "raw" (json.RawMessage)`)
+	marshalersFlag = flag.Bool("marshalers", false,
+		"in addition to the struct definition(s), generate hand-rolled MarshalJSON/UnmarshalJSON methods for each generated type")
+	disallowUnknown = flag.Bool("disallow-unknown", false,
+		"with -marshalers, make UnmarshalJSON return an error for JSON object keys that don't match any field")
+	pathFlag = flag.String("path", "",
+		`a gjson-style selector (e.g. "data.items.#" or "response.user") scoping generation to a sub-value of the input document, instead of the root`)
+	validateStyleString = flag.String("validate-style", string(StyleNone),
+		fmt.Sprintf(`with -input=schema, whether to emit go-playground/validator "validate:" tags derived from schema constraints: "%s" or "%s"`,
+			StyleNone, "tags"))
 )
 
-// Given a JSON string representation of an object and a name structName,
-// attemp to generate a struct definition
-func generate(input io.Reader, structName, pkgName string) ([]byte, error) {
-	var iresult interface{}
-	var result map[string]interface{}
-	if err := json.NewDecoder(input).Decode(&iresult); err != nil {
-		return nil, err
-	}
+// shape accumulates the set of JSON value kinds observed for a single field
+// path across every sample merged into it. Merging more than one sample
+// through the same shape is how gojson infers which fields are optional
+// (present in fewer samples than their parent object) and how it unifies
+// array element types and conflicting scalar types, instead of trusting
+// objects[0] the way typeForValue used to.
+type shape struct {
+	seen    int // times a value was merged at this path
+	objSeen int // of those, times the value was an object
+	floats  bool
+	strings bool
+	bools   bool
+	object  map[string]*shape
+	array   *shape
 
-	switch iresult := iresult.(type) {
-	case map[string]interface{}:
-		result = iresult
-	case []map[string]interface{}:
-		if len(iresult) > 0 {
-			result = iresult[0]
+	// Number sub-classification, valid when floats is true.
+	numSeen     bool // a number has been merged at least once
+	allInt      bool // every number seen so far is written as an integer (see anyBigInt for int64 overflow)
+	anyBigInt   bool // some integral number overflows int64
+	anyBigFloat bool // some non-integer number loses precision in float64
+
+	// String sub-classification, valid when strings is true. Each "all*"
+	// flag starts true on the first string merged and is ANDed with every
+	// string seen after, so it ends up true only if every sample agreed.
+	strSeen     bool
+	allDateTime bool
+	allDuration bool
+	allUUID     bool
+}
+
+func (s *shape) merge(v interface{}) {
+	s.seen++
+	switch val := v.(type) {
+	case nil:
+		// null doesn't constrain the type; seen alone keeps the field present
+	case json.Number:
+		s.floats = true
+		isInt, isBigInt, isBigFloat := classifyNumber(val)
+		if !s.numSeen {
+			s.numSeen = true
+			s.allInt = isInt
 		} else {
-			return nil, fmt.Errorf("empty array")
+			s.allInt = s.allInt && isInt
 		}
-	default:
-		return nil, fmt.Errorf("unexpected type: %T", iresult)
+		s.anyBigInt = s.anyBigInt || isBigInt
+		s.anyBigFloat = s.anyBigFloat || isBigFloat
+	case string:
+		s.strings = true
+		isDateTime := looksLikeDateTime(val)
+		isDuration := !isDateTime && looksLikeDuration(val)
+		isUUID := !isDateTime && !isDuration && looksLikeUUID(val)
+		if !s.strSeen {
+			s.strSeen = true
+			s.allDateTime = isDateTime
+			s.allDuration = isDuration
+			s.allUUID = isUUID
+		} else {
+			s.allDateTime = s.allDateTime && isDateTime
+			s.allDuration = s.allDuration && isDuration
+			s.allUUID = s.allUUID && isUUID
+		}
+	case bool:
+		s.bools = true
+	case map[string]interface{}:
+		s.objSeen++
+		if s.object == nil {
+			s.object = make(map[string]*shape)
+		}
+		for k, fv := range val {
+			child, ok := s.object[k]
+			if !ok {
+				child = &shape{}
+				s.object[k] = child
+			}
+			child.merge(fv)
+		}
+	case []interface{}:
+		if s.array == nil {
+			s.array = &shape{}
+		}
+		for _, ev := range val {
+			s.array.merge(ev)
+		}
+	}
+}
+
+// readSamples decodes every top-level JSON value on r (NDJSON, or a single
+// document) into a slice of generic values to be merged into one shape.
+func readSamples(r io.Reader) ([]interface{}, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var samples []interface{}
+	for {
+		var v interface{}
+		if err := dec.Decode(&v); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		samples = append(samples, v)
+	}
+	return samples, nil
+}
+
+// selectPath evaluates a gjson-style path (already split on ".") against a
+// decoded JSON value, returning the value(s) it selects. A plain segment
+// selects an object key; a numeric segment selects an array index; "#"
+// selects every element of an array, continuing to evaluate the remaining
+// segments against each one and flattening the results together, so that
+// e.g. "items.#" unifies the shape of every element of the items array.
+func selectPath(v interface{}, segments []string) ([]interface{}, error) {
+	if len(segments) == 0 {
+		return []interface{}{v}, nil
+	}
+	seg, rest := segments[0], segments[1:]
+
+	if seg == "#" {
+		arr, ok := v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("path segment %q: expected array, got %T", seg, v)
+		}
+		var out []interface{}
+		for _, elem := range arr {
+			vals, err := selectPath(elem, rest)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, vals...)
+		}
+		return out, nil
+	}
+
+	if idx, err := strconv.Atoi(seg); err == nil {
+		arr, ok := v.([]interface{})
+		if !ok || idx < 0 || idx >= len(arr) {
+			return nil, fmt.Errorf("path segment %q: index out of range for %T", seg, v)
+		}
+		return selectPath(arr[idx], rest)
+	}
+
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("path segment %q: expected object, got %T", seg, v)
+	}
+	child, ok := obj[seg]
+	if !ok {
+		return nil, fmt.Errorf("path segment %q: no such key", seg)
+	}
+	return selectPath(child, rest)
+}
+
+// buildRootShape reads one or more JSON samples (from input, or from
+// -samples files), scopes each to *pathFlag if set, and merges the result
+// into a single root shape.
+func buildRootShape(input io.Reader) (*shape, error) {
+	var samples []interface{}
+
+	if *samplesFlag != "" {
+		for _, path := range strings.Split(*samplesFlag, ",") {
+			f, err := os.Open(path)
+			if err != nil {
+				return nil, err
+			}
+			fileSamples, err := readSamples(f)
+			f.Close()
+			if err != nil {
+				return nil, err
+			}
+			samples = append(samples, fileSamples...)
+		}
+	} else {
+		var err error
+		if samples, err = readSamples(input); err != nil {
+			return nil, err
+		}
+	}
+
+	root := &shape{}
+	for _, s := range samples {
+		values := []interface{}{s}
+		if *pathFlag != "" {
+			var err error
+			if values, err = selectPath(s, strings.Split(*pathFlag, ".")); err != nil {
+				return nil, err
+			}
+		}
+		for _, v := range values {
+			root.merge(v)
+		}
+	}
+	if root.object == nil {
+		return nil, fmt.Errorf("unexpected type: no JSON object found in input")
+	}
+	return root, nil
+}
+
+// Given one or more JSON samples (read from input, or from -samples files)
+// and a name structName, attempt to generate a struct definition that
+// unifies every sample's shape.
+func generate(input io.Reader, structName, pkgName string) ([]byte, error) {
+	root, err := buildRootShape(input)
+	if err != nil {
+		return nil, err
 	}
 
-	src := fmt.Sprintf("package %s\ntype %s %s}",
+	usedImports = map[string]bool{}
+	body := generateTypes(root, 0)
+	src := fmt.Sprintf("package %s\n%stype %s %s}",
 		pkgName,
+		renderImportBlock(),
 		structName,
-		generateTypes(result, 0))
+		body)
 	formatted, err := format.Source([]byte(src))
 	if err != nil {
 		err = fmt.Errorf("error formatting: %s, was formatting\n%s", err, src)
@@ -117,6 +327,127 @@ func generate(input io.Reader, structName, pkgName string) ([]byte, error) {
 	return formatted, err
 }
 
+// renderImportBlock renders the import ( ... ) block needed for any richer
+// scalar types (time.Time, *big.Int, a configured UUID type, ...) that
+// generateTypes ended up using, or "" if none were needed.
+func renderImportBlock() string {
+	if len(usedImports) == 0 {
+		return ""
+	}
+	imports := make([]string, 0, len(usedImports))
+	for imp := range usedImports {
+		imports = append(imports, imp)
+	}
+	sort.Strings(imports)
+
+	var b strings.Builder
+	b.WriteString("import (\n")
+	for _, imp := range imports {
+		fmt.Fprintf(&b, "\t%q\n", imp)
+	}
+	b.WriteString(")\n")
+	return b.String()
+}
+
+// typeLister lifts every object shape reachable from a root shape into a
+// named top-level emitter.Type, rather than the anonymous inline structs
+// generateTypes produces, so that -marshalers has a named type per struct
+// to attach MarshalJSON/UnmarshalJSON methods to.
+type typeLister struct {
+	types []emitter.Type
+	seen  map[string]bool
+}
+
+func (tl *typeLister) reserve(name string) string {
+	if tl.seen == nil {
+		tl.seen = map[string]bool{}
+	}
+	for tl.seen[name] {
+		name += "_"
+	}
+	tl.seen[name] = true
+	return name
+}
+
+func (tl *typeLister) lift(hint string, s *shape) string {
+	if kindsSeen(s) > 1 {
+		if *conflictPolicy == "raw" {
+			return "json.RawMessage"
+		}
+		return "interface{}"
+	}
+	switch {
+	case s.object != nil:
+		return tl.liftObject(fmtFieldName(hint, true), s)
+	case s.array != nil:
+		return "[]" + tl.lift(singularize(hint), s.array)
+	case s.floats:
+		return numberType(s)
+	case s.strings:
+		return stringType(s)
+	case s.bools:
+		return "bool"
+	default:
+		return "interface{}"
+	}
+}
+
+// liftObject registers a named struct type for s, using name verbatim
+// (reserving a non-clashing variant if it's already taken), and returns
+// that name.
+func (tl *typeLister) liftObject(name string, s *shape) string {
+	name = tl.reserve(name)
+
+	keys := make([]string, 0, len(s.object))
+	for key := range s.object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fields := make([]emitter.Field, 0, len(keys))
+	for _, key := range keys {
+		child := s.object[key]
+		optional := child.seen < s.objSeen
+		goType := tl.lift(key, child)
+		if optional && isScalarType(goType) {
+			goType = "*" + goType
+		}
+		fields = append(fields, emitter.Field{
+			GoName:    fmtFieldName(key, true),
+			JSONKey:   key,
+			GoType:    goType,
+			Omitempty: optional,
+		})
+	}
+
+	tl.types = append(tl.types, emitter.Type{Name: name, Fields: fields})
+	return name
+}
+
+// generateWithMarshalers is the -marshalers counterpart to generate: it
+// lifts every nested object into its own named type and asks the emitter
+// package to render each one together with hand-rolled (un)marshal methods.
+func generateWithMarshalers(input io.Reader, structName, pkgName string) ([]byte, error) {
+	root, err := buildRootShape(input)
+	if err != nil {
+		return nil, err
+	}
+
+	usedImports = map[string]bool{}
+	tl := &typeLister{}
+	tl.liftObject(structName, root)
+
+	extraImports := make([]string, 0, len(usedImports))
+	for imp := range usedImports {
+		extraImports = append(extraImports, imp)
+	}
+
+	return emitter.Generate(tl.types, pkgName, emitter.Options{
+		DisallowUnknown: *disallowUnknown,
+		ExtraImports:    extraImports,
+	})
+}
+
 func lowerFirst(s string) string {
 	if s == "" {
 		return ""
@@ -125,26 +456,26 @@ func lowerFirst(s string) string {
 	return string(unicode.ToLower(r)) + s[n:]
 }
 
-// Generate go struct entries for a map[string]interface{} structure
-func generateTypes(obj map[string]interface{}, depth int) string {
+// Generate go struct entries for an object shape merged from one or more samples
+func generateTypes(obj *shape, depth int) string {
 	structure := "struct {"
 
-	keys := make([]string, 0, len(obj))
-	for key := range obj {
+	keys := make([]string, 0, len(obj.object))
+	for key := range obj.object {
 		keys = append(keys, key)
 	}
 	sort.Strings(keys)
 
 	for _, key := range keys {
-		value := obj[key]
-		valueType := typeForValue(value)
-
-		//If a nested value, recurse
-		switch value := value.(type) {
-		case []map[string]interface{}:
-			valueType = "[]" + generateTypes(value[0], depth+1) + "}"
-		case map[string]interface{}:
-			valueType = generateTypes(value, depth+1) + "}"
+		child := obj.object[key]
+		optional := child.seen < obj.objSeen
+		valueType := typeForShape(child, depth+1)
+		if optional && (isScalarType(valueType) || strings.HasPrefix(valueType, "struct {")) {
+			// A struct-shaped field needs the same pointer treatment as a
+			// scalar: encoding/json never treats a non-pointer struct as
+			// "empty", so without this the "omitempty" tag below would be
+			// a no-op for an object absent from some samples.
+			valueType = "*" + valueType
 		}
 
 		fieldName := fmtFieldName(key, true)
@@ -163,6 +494,9 @@ func generateTypes(obj map[string]interface{}, depth int) string {
 				if *bsonExtra != "" {
 					fieldName += "," + *bsonExtra
 				}
+				if optional && !strings.Contains(fieldName, "omitempty") {
+					fieldName += ",omitempty"
+				}
 				structure += fmt.Sprintf(`bson:"%s"`, fieldName)
 
 				if jsonStyle != StyleNone {
@@ -179,6 +513,9 @@ func generateTypes(obj map[string]interface{}, depth int) string {
 				if *jsonExtra != "" {
 					fieldName += "," + *jsonExtra
 				}
+				if optional && !strings.Contains(fieldName, "omitempty") {
+					fieldName += ",omitempty"
+				}
 				structure += fmt.Sprintf(`json:"%s"`, fieldName)
 			}
 			structure += "`"
@@ -219,24 +556,187 @@ func fmtFieldName(s string, fixUpper bool) string {
 	return string(runes)
 }
 
-// generate an appropriate struct type entry
-func typeForValue(value interface{}) string {
-	//Check if this is an array
-	if objects, ok := value.([]interface{}); ok {
-		types := make(map[reflect.Type]bool, 0)
-		for _, o := range objects {
-			types[reflect.TypeOf(o)] = true
+// singularize makes a best-effort attempt to turn a plural field name (used
+// to name an array) into a singular one, for naming its element type.
+func singularize(s string) string {
+	switch {
+	case strings.HasSuffix(s, "ies"):
+		return s[:len(s)-3] + "y"
+	case strings.HasSuffix(s, "ses"):
+		return s[:len(s)-2]
+	case strings.HasSuffix(s, "s") && !strings.HasSuffix(s, "ss"):
+		return s[:len(s)-1]
+	}
+	return s
+}
+
+// isScalarType reports whether t is one of the plain scalar types gojson
+// emits, as opposed to a struct, slice, map or interface{}.
+func isScalarType(t string) bool {
+	switch t {
+	case "float64", "int64", "string", "bool", "json.Number", "time.Time", "time.Duration":
+		return true
+	}
+	_, uuidSelector := qualifiedType(*uuidType)
+	return t == uuidSelector
+}
+
+// usedImports accumulates the extra (non-stdlib-default) imports that the
+// richer scalar types below need, so generate and generateWithMarshalers can
+// add them to the emitted file's import block.
+var usedImports map[string]bool
+
+func markImport(path string) {
+	if usedImports == nil {
+		usedImports = map[string]bool{}
+	}
+	usedImports[path] = true
+}
+
+// classifyNumber inspects the literal text of a json.Number (decoded with
+// UseNumber so no precision is lost up front) and reports whether it's
+// written as an integer (no fraction or exponent), whether such an integer
+// overflows int64, and whether any other number's value is not held
+// exactly by float64.
+func classifyNumber(n json.Number) (isInt, isBigInt, isBigFloat bool) {
+	str := string(n)
+	if !strings.ContainsAny(str, ".eE") {
+		if _, err := n.Int64(); err != nil {
+			return true, true, false
 		}
-		if len(types) == 1 {
-			return "[]" + typeForValue(objects[0])
+		return true, false, false
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return false, false, true
+	}
+	// Compare values, not text, so that exact literals such as "10.0" or
+	// "1e5" are not mistaken for ones float64 can't hold.
+	literal, ok := new(big.Rat).SetString(str)
+	roundTrip, _ := new(big.Rat).SetString(strconv.FormatFloat(f, 'g', -1, 64))
+	if !ok || roundTrip == nil || literal.Cmp(roundTrip) != 0 {
+		return false, false, true
+	}
+	return false, false, false
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+func looksLikeDateTime(s string) bool {
+	_, err := time.Parse(time.RFC3339, s)
+	return err == nil
+}
+
+func looksLikeDuration(s string) bool {
+	_, err := time.ParseDuration(s)
+	return err == nil
+}
+
+func looksLikeUUID(s string) bool {
+	return uuidPattern.MatchString(s)
+}
+
+// qualifiedType turns an import-qualified type reference like
+// "github.com/google/uuid.UUID" into its import path and the selector to
+// use at the point of reference ("uuid.UUID"). A bare type name such as
+// "string" has no import path and is returned as-is.
+func qualifiedType(t string) (importPath, selector string) {
+	dot := strings.LastIndex(t, ".")
+	slash := strings.LastIndex(t, "/")
+	if dot == -1 || dot < slash {
+		return "", t
+	}
+	importPath = t[:dot]
+	pkgAlias := importPath[strings.LastIndex(importPath, "/")+1:]
+	return importPath, pkgAlias + "." + t[dot+1:]
+}
+
+// numberType resolves the Go type for a shape whose samples were all
+// numbers, widening int64/float64 to json.Number or *big.Int when a sample
+// couldn't be represented exactly.
+func numberType(s *shape) string {
+	switch {
+	case s.anyBigInt:
+		markImport("math/big")
+		return "*big.Int"
+	case s.anyBigFloat:
+		markImport("encoding/json")
+		return "json.Number"
+	case s.allInt:
+		return "int64"
+	default:
+		return "float64"
+	}
+}
+
+// stringType resolves the Go type for a shape whose samples were all
+// strings, recognizing RFC3339 date-times, time.ParseDuration-shaped
+// durations, and UUID-shaped strings.
+func stringType(s *shape) string {
+	switch {
+	case s.allDateTime:
+		markImport("time")
+		return "time.Time"
+	case s.allDuration:
+		markImport("time")
+		return "time.Duration"
+	case s.allUUID:
+		importPath, selector := qualifiedType(*uuidType)
+		if importPath != "" {
+			markImport(importPath)
+		}
+		return selector
+	default:
+		return "string"
+	}
+}
+
+// kindsSeen counts how many distinct JSON value kinds were merged into s;
+// more than one means its samples disagreed on type.
+func kindsSeen(s *shape) int {
+	kinds := 0
+	if s.floats {
+		kinds++
+	}
+	if s.strings {
+		kinds++
+	}
+	if s.bools {
+		kinds++
+	}
+	if s.object != nil {
+		kinds++
+	}
+	if s.array != nil {
+		kinds++
+	}
+	return kinds
+}
+
+// typeForShape resolves the Go type for a merged shape. If the shape's
+// samples disagreed on type (e.g. one sample had a string where another had
+// a number), it widens to interface{} or, with -conflict-policy=raw, to
+// json.RawMessage.
+func typeForShape(s *shape, depth int) string {
+	switch {
+	case kindsSeen(s) == 0:
+		return "interface{}"
+	case kindsSeen(s) > 1:
+		if *conflictPolicy == "raw" {
+			return "json.RawMessage"
 		}
-		return "[]interface{}"
-	} else if object, ok := value.(map[string]interface{}); ok {
-		return generateTypes(object, 0) + "}"
-	} else if reflect.TypeOf(value) == nil {
 		return "interface{}"
+	case s.object != nil:
+		return generateTypes(s, depth) + "}"
+	case s.array != nil:
+		return "[]" + typeForShape(s.array, depth+1)
+	case s.floats:
+		return numberType(s)
+	case s.strings:
+		return stringType(s)
+	default: // s.bools
+		return "bool"
 	}
-	return reflect.TypeOf(value).Name()
 }
 
 // Return true if os.Stdin appears to be interactive
@@ -261,17 +761,35 @@ func main() {
 	bsonStyle = Style(*bsonStyleString)
 
 	if !IsValidStyle(jsonStyle) {
-		fmt.Fprintln(os.Stderr, `Invalid json style: "%s"`, jsonStyle)
+		fmt.Fprintf(os.Stderr, "Invalid json style: %q\n", jsonStyle)
 	}
 
 	if !IsValidStyle(bsonStyle) {
-		fmt.Fprintln(os.Stderr, `Invalid bson style: "%s"`, bsonStyle)
+		fmt.Fprintf(os.Stderr, "Invalid bson style: %q\n", bsonStyle)
 	}
 
-	if output, err := generate(os.Stdin, *name, *pkg); err != nil {
+	var output []byte
+	var err error
+	switch *inputMode {
+	case "example":
+		if *marshalersFlag {
+			output, err = generateWithMarshalers(os.Stdin, *name, *pkg)
+		} else {
+			output, err = generate(os.Stdin, *name, *pkg)
+		}
+	case "schema":
+		output, err = schema.Generate(os.Stdin, *name, *pkg, schema.Options{
+			UUIDType: *uuidType,
+			Validate: Style(*validateStyleString) != StyleNone,
+		})
+	default:
+		fmt.Fprintf(os.Stderr, `Invalid input mode: "%s"`+"\n", *inputMode)
+		os.Exit(1)
+	}
+
+	if err != nil {
 		fmt.Fprintln(os.Stderr, "error parsing", err)
 		os.Exit(1)
-	} else {
-		fmt.Print(string(output))
 	}
+	fmt.Print(string(output))
 }
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// num is a convenience constructor for the json.Number values shape.merge
+// expects, mirroring what readSamples (which decodes with UseNumber) would
+// produce.
+func num(s string) json.Number {
+	return json.Number(s)
+}
+
+func TestShapeMergeOptionalField(t *testing.T) {
+	root := &shape{}
+	root.merge(map[string]interface{}{"id": num("1"), "name": "alice"})
+	root.merge(map[string]interface{}{"id": num("2")})
+
+	if root.seen != 2 || root.objSeen != 2 {
+		t.Fatalf("root: seen=%d objSeen=%d, want 2/2", root.seen, root.objSeen)
+	}
+
+	id, ok := root.object["id"]
+	if !ok || id.seen != 2 {
+		t.Fatalf("id field: got %+v, want seen=2 (present in every sample)", id)
+	}
+
+	name, ok := root.object["name"]
+	if !ok || name.seen != 1 {
+		t.Fatalf("name field: got %+v, want seen=1 (present in only one sample)", name)
+	}
+}
+
+func TestShapeMergeNumberClassification(t *testing.T) {
+	tests := []struct {
+		name    string
+		samples []json.Number
+		allInt  bool
+	}{
+		{"all integers", []json.Number{num("1"), num("2"), num("3")}, true},
+		{"mixed int and float", []json.Number{num("1"), num("2.5")}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &shape{}
+			for _, n := range tt.samples {
+				s.merge(n)
+			}
+			if s.allInt != tt.allInt {
+				t.Errorf("allInt = %v, want %v", s.allInt, tt.allInt)
+			}
+			if !s.floats || !s.numSeen {
+				t.Errorf("floats=%v numSeen=%v, want both true", s.floats, s.numSeen)
+			}
+		})
+	}
+}
+
+func TestShapeMergeStringClassification(t *testing.T) {
+	tests := []struct {
+		name        string
+		samples     []string
+		allDateTime bool
+		allDuration bool
+		allUUID     bool
+	}{
+		{"all RFC3339 timestamps", []string{"2021-01-01T00:00:00Z", "2022-06-15T12:30:00Z"}, true, false, false},
+		{"all durations", []string{"1h30m", "5s"}, false, true, false},
+		{"all uuids", []string{"123e4567-e89b-12d3-a456-426614174000", "00000000-0000-0000-0000-000000000000"}, false, false, true},
+		{"plain strings", []string{"alice", "bob"}, false, false, false},
+		{"one timestamp, one plain string disagrees", []string{"2021-01-01T00:00:00Z", "alice"}, false, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &shape{}
+			for _, v := range tt.samples {
+				s.merge(v)
+			}
+			if s.allDateTime != tt.allDateTime {
+				t.Errorf("allDateTime = %v, want %v", s.allDateTime, tt.allDateTime)
+			}
+			if s.allDuration != tt.allDuration {
+				t.Errorf("allDuration = %v, want %v", s.allDuration, tt.allDuration)
+			}
+			if s.allUUID != tt.allUUID {
+				t.Errorf("allUUID = %v, want %v", s.allUUID, tt.allUUID)
+			}
+		})
+	}
+}
+
+func TestGenerateTypesOptionalObjectFieldIsPointer(t *testing.T) {
+	root := &shape{}
+	root.merge(map[string]interface{}{"name": "a", "meta": map[string]interface{}{"tag": "x"}})
+	root.merge(map[string]interface{}{"name": "b"})
+
+	out := generateTypes(root, 0)
+	if !strings.Contains(out, "Meta *struct {") {
+		t.Errorf("expected Meta to be a pointer-typed struct field (so omitempty isn't a no-op), got:\n%s", out)
+	}
+}
+
+func TestShapeMergeTypeConflictWidensKind(t *testing.T) {
+	s := &shape{}
+	s.merge("a string")
+	s.merge(num("1"))
+
+	if kinds := kindsSeen(s); kinds != 2 {
+		t.Fatalf("kindsSeen = %d, want 2 (string and number both seen)", kinds)
+	}
+	if got := typeForShape(s, 0); got != "interface{}" {
+		t.Errorf("typeForShape = %q, want interface{} (the default -conflict-policy)", got)
+	}
+}
+
+func TestSelectPath(t *testing.T) {
+	doc := map[string]interface{}{
+		"data": map[string]interface{}{
+			"items": []interface{}{
+				map[string]interface{}{"id": num("1")},
+				map[string]interface{}{"id": num("2")},
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		path    []string
+		want    []interface{}
+		wantErr bool
+	}{
+		{
+			name: "object key then flattened array",
+			path: []string{"data", "items", "#"},
+			want: []interface{}{
+				map[string]interface{}{"id": num("1")},
+				map[string]interface{}{"id": num("2")},
+			},
+		},
+		{
+			name: "numeric index",
+			path: []string{"data", "items", "1"},
+			want: []interface{}{map[string]interface{}{"id": num("2")}},
+		},
+		{
+			name:    "missing key",
+			path:    []string{"data", "nope"},
+			wantErr: true,
+		},
+		{
+			name:    "index out of range",
+			path:    []string{"data", "items", "5"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectPath(doc, tt.path)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got nil error, want one")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyNumber(t *testing.T) {
+	tests := []struct {
+		name                        string
+		n                           json.Number
+		isInt, isBigInt, isBigFloat bool
+	}{
+		{"small int", num("42"), true, false, false},
+		{"overflows int64", num("99999999999999999999"), true, true, false},
+		{"exact float", num("1.5"), false, false, false},
+		{"float loses precision", num("1.00000000000000000001"), false, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isInt, isBigInt, isBigFloat := classifyNumber(tt.n)
+			if isInt != tt.isInt || isBigInt != tt.isBigInt || isBigFloat != tt.isBigFloat {
+				t.Errorf("classifyNumber(%s) = (%v, %v, %v), want (%v, %v, %v)",
+					tt.n, isInt, isBigInt, isBigFloat, tt.isInt, tt.isBigInt, tt.isBigFloat)
+			}
+		})
+	}
+}
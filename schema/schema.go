@@ -0,0 +1,595 @@
+// Package schema generates Go struct definitions from a JSON Schema
+// (draft-07 / 2020-12) document, as opposed to the example-driven
+// generation in the main gojson package.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Options configures schema-driven generation.
+type Options struct {
+	// UUIDType is the Go type emitted for `format: uuid` string schemas.
+	// Defaults to "string" if empty.
+	UUIDType string
+	// Validate emits go-playground/validator `validate:"..."` tags derived
+	// from schema constraints, alongside the `json:` tag.
+	Validate bool
+}
+
+// Schema is a (partial) representation of a JSON Schema document, covering
+// the keywords gojson knows how to translate into Go types.
+type Schema struct {
+	ID                   string             `json:"$id,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Type                 interface{}        `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Definitions          map[string]*Schema `json:"definitions,omitempty"`
+	Defs                 map[string]*Schema `json:"$defs,omitempty"`
+
+	MinLength *int     `json:"minLength,omitempty"`
+	MaxLength *int     `json:"maxLength,omitempty"`
+	Minimum   *float64 `json:"minimum,omitempty"`
+	Maximum   *float64 `json:"maximum,omitempty"`
+	Pattern   string   `json:"pattern,omitempty"`
+}
+
+// validatorFormats maps JSON Schema `format` values to the go-playground/
+// validator alias that checks the same constraint.
+var validatorFormats = map[string]string{
+	"email": "email",
+	"uri":   "uri",
+	"uuid":  "uuid",
+}
+
+// validateTag renders the go-playground/validator tag content for s, given
+// whether the field is required, or "" if s carries no constraints.
+func validateTag(s *Schema, required bool) string {
+	var parts []string
+	if required {
+		parts = append(parts, "required")
+	}
+	if s.MinLength != nil {
+		parts = append(parts, fmt.Sprintf("min=%d", *s.MinLength))
+	}
+	if s.MaxLength != nil {
+		parts = append(parts, fmt.Sprintf("max=%d", *s.MaxLength))
+	}
+	if s.Minimum != nil {
+		parts = append(parts, fmt.Sprintf("gte=%v", *s.Minimum))
+	}
+	if s.Maximum != nil {
+		parts = append(parts, fmt.Sprintf("lte=%v", *s.Maximum))
+	}
+	if s.Pattern != "" && !strings.ContainsAny(s.Pattern, ",=") {
+		// go-playground/validator has no way to escape "," or "=" inside a
+		// "regexp=" tag value (the struct tag format uses both as its own
+		// delimiters), so a pattern containing either - e.g. any bounded
+		// quantifier like "{2,4}" - would silently split into bogus rules
+		// instead of being matched literally. Drop it rather than emit a
+		// tag that misvalidates.
+		parts = append(parts, fmt.Sprintf("regexp=%s", s.Pattern))
+	}
+	if alias, ok := validatorFormats[s.Format]; ok {
+		parts = append(parts, alias)
+	}
+	if len(s.Enum) > 0 {
+		values := make([]string, 0, len(s.Enum))
+		for _, v := range s.Enum {
+			values = append(values, fmt.Sprintf("%v", v))
+		}
+		parts = append(parts, "oneof="+strings.Join(values, " "))
+	}
+	return strings.Join(parts, ",")
+}
+
+// types lists the JSON Schema "type" keyword as a normalized slice, since
+// the keyword may be a single string or an array of strings.
+func (s *Schema) types() []string {
+	switch t := s.Type.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		out := make([]string, 0, len(t))
+		for _, v := range t {
+			if str, ok := v.(string); ok {
+				out = append(out, str)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func (s *Schema) hasType(name string) bool {
+	for _, t := range s.types() {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+// generator holds the state accumulated while walking a schema: the root
+// document (for $ref resolution), the named top-level types produced so
+// far, and the set of imports the emitted file needs.
+type generator struct {
+	root    *Schema
+	opts    Options
+	order   []string          // type names, in emission order
+	decls   map[string]string // type name -> full declaration (type ... plus any consts)
+	refs    map[string]string // "#/definitions/Foo" -> resolved type name
+	imports map[string]bool
+}
+
+// Generate reads a JSON Schema document from r and emits Go source defining
+// rootName (and any named types it references) in package pkgName.
+func Generate(r io.Reader, rootName, pkgName string, opts Options) ([]byte, error) {
+	var root Schema
+	if err := json.NewDecoder(r).Decode(&root); err != nil {
+		return nil, err
+	}
+	if opts.UUIDType == "" {
+		opts.UUIDType = "string"
+	}
+
+	g := &generator{
+		root:    &root,
+		opts:    opts,
+		decls:   map[string]string{},
+		refs:    map[string]string{},
+		imports: map[string]bool{},
+	}
+
+	if _, err := g.namedType(rootName, &root, true); err != nil {
+		return nil, err
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "package %s\n\n", pkgName)
+	if len(g.imports) > 0 {
+		imports := make([]string, 0, len(g.imports))
+		for imp := range g.imports {
+			imports = append(imports, imp)
+		}
+		sort.Strings(imports)
+		body.WriteString("import (\n")
+		for _, imp := range imports {
+			fmt.Fprintf(&body, "\t%q\n", imp)
+		}
+		body.WriteString(")\n\n")
+	}
+	for _, name := range g.order {
+		body.WriteString(g.decls[name])
+		body.WriteString("\n\n")
+	}
+
+	formatted, err := format.Source([]byte(body.String()))
+	if err != nil {
+		return nil, fmt.Errorf("error formatting: %s, was formatting\n%s", err, body.String())
+	}
+	return formatted, nil
+}
+
+// namedType registers a new top-level type for s under name (suffixed by
+// reserveName if that name is already declared; a root $ref becomes an
+// alias of the resolved definition) and returns the name to use when
+// referencing it from a field.
+func (g *generator) namedType(name string, s *Schema, top bool) (string, error) {
+	if top && s.Ref != "" {
+		target, err := g.resolveRef(s.Ref)
+		if err != nil {
+			return "", err
+		}
+		name = g.reserveName(name)
+		g.declare(name, fmt.Sprintf("type %s = %s", name, target))
+		return name, nil
+	}
+
+	return g.declareNamed(g.reserveName(name), s)
+}
+
+// reserveName returns an exported identifier for name, suffixed with "_" as
+// many times as needed to avoid colliding with a type already declared.
+func (g *generator) reserveName(name string) string {
+	name = exportedName(name)
+	for {
+		if _, exists := g.decls[name]; !exists {
+			return name
+		}
+		name += "_"
+	}
+}
+
+// declareNamed renders and registers the declaration for s under the given
+// name, which callers must already have reserved (via reserveName) against
+// collisions. Kept separate from namedType so resolveRef can reserve a
+// ref's name before recursing into its body.
+func (g *generator) declareNamed(name string, s *Schema) (string, error) {
+	switch {
+	case len(s.Enum) > 0:
+		decl, err := g.enumDecl(name, s)
+		if err != nil {
+			return "", err
+		}
+		g.declare(name, decl)
+		return name, nil
+
+	case len(s.OneOf) > 0 || len(s.AnyOf) > 0:
+		return g.unionDecl(name, s)
+
+	default:
+		body, err := g.structBody(name, s)
+		if err != nil {
+			return "", err
+		}
+		g.declare(name, fmt.Sprintf("type %s %s", name, body))
+		return name, nil
+	}
+}
+
+func (g *generator) declare(name, decl string) {
+	if _, exists := g.decls[name]; exists {
+		return
+	}
+	g.order = append(g.order, name)
+	g.decls[name] = decl
+}
+
+// structBody renders the `struct { ... }` body for an object schema,
+// sorting fields by name for deterministic output.
+func (g *generator) structBody(name string, s *Schema) (string, error) {
+	if s.Properties == nil {
+		if s.AdditionalProperties != nil {
+			elem, err := g.typeFor(name, s.AdditionalProperties, true)
+			if err != nil {
+				return "", err
+			}
+			return "map[string]" + elem, nil
+		}
+		return "map[string]interface{}", nil
+	}
+
+	required := map[string]bool{}
+	for _, r := range s.Required {
+		required[r] = true
+	}
+
+	keys := make([]string, 0, len(s.Properties))
+	for k := range s.Properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString("struct {")
+	for _, key := range keys {
+		prop := s.Properties[key]
+		goType, err := g.typeFor(key, prop, required[key])
+		if err != nil {
+			return "", err
+		}
+		fieldName := exportedName(key)
+		tag := key
+		if !required[key] {
+			tag += ",omitempty"
+		}
+
+		structTag := fmt.Sprintf("json:%q", tag)
+		if g.opts.Validate {
+			if v := validateTag(prop, required[key]); v != "" {
+				structTag += fmt.Sprintf(` validate:%q`, v)
+			}
+		}
+		fmt.Fprintf(&b, "\n%s %s `%s`", fieldName, goType, structTag)
+	}
+	b.WriteString("\n}")
+	return b.String(), nil
+}
+
+// typeFor resolves the Go type to use for a property named nameHint with
+// schema s, lifting nested objects/enums/unions into named top-level types
+// as it goes.
+func (g *generator) typeFor(nameHint string, s *Schema, required bool) (string, error) {
+	if s.Ref != "" {
+		name, err := g.resolveRef(s.Ref)
+		if err != nil {
+			return "", err
+		}
+		// Always pointer, regardless of required: a $ref may point into a
+		// self- or mutually-referential cycle (e.g. a tree node's
+		// "child"), and Go can't embed a struct inside itself by value, so
+		// the pointer-vs-value decision can't depend on whether this
+		// particular field happens to be required.
+		return "*" + name, nil
+	}
+
+	if len(s.Enum) > 0 {
+		name, err := g.namedType(nameHint, s, false)
+		if err != nil {
+			return "", err
+		}
+		return name, nil
+	}
+
+	if len(s.OneOf) > 0 || len(s.AnyOf) > 0 {
+		return g.namedType(nameHint, s, false)
+	}
+
+	nullable := s.hasType("null")
+
+	switch {
+	case s.hasType("object") || (s.Type == nil && (s.Properties != nil || s.AdditionalProperties != nil)):
+		name, err := g.namedType(nameHint, s, false)
+		if err != nil {
+			return "", err
+		}
+		if (!required || nullable) && s.Properties != nil {
+			// Without this, an optional struct-shaped field (one with
+			// "properties") keeps a non-pointer Go type, and
+			// encoding/json never treats a non-pointer struct as "empty"
+			// - so the "omitempty" structBody already adds for it would
+			// be a no-op. A map-shaped object (no "properties") doesn't
+			// need this: encoding/json already omits a nil map.
+			return "*" + name, nil
+		}
+		return name, nil
+
+	case s.hasType("array") || (s.Type == nil && s.Items != nil):
+		if s.Items == nil {
+			return "[]interface{}", nil
+		}
+		elem, err := g.typeFor(singularize(nameHint), s.Items, true)
+		if err != nil {
+			return "", err
+		}
+		return "[]" + elem, nil
+
+	case s.hasType("string"):
+		goType := g.stringType(s)
+		if !required || nullable {
+			return "*" + goType, nil
+		}
+		return goType, nil
+
+	case s.hasType("integer"):
+		if !required || nullable {
+			return "*int64", nil
+		}
+		return "int64", nil
+
+	case s.hasType("number"):
+		if !required || nullable {
+			return "*float64", nil
+		}
+		return "float64", nil
+
+	case s.hasType("boolean"):
+		if !required || nullable {
+			return "*bool", nil
+		}
+		return "bool", nil
+
+	default:
+		return "interface{}", nil
+	}
+}
+
+func (g *generator) stringType(s *Schema) string {
+	switch s.Format {
+	case "date-time", "date", "time":
+		g.imports["time"] = true
+		return "time.Time"
+	case "uuid":
+		importPath, selector := splitQualifiedType(g.opts.UUIDType)
+		if importPath != "" {
+			g.imports[importPath] = true
+		}
+		return selector
+	default:
+		return "string"
+	}
+}
+
+// splitQualifiedType turns an import-qualified type reference like
+// "github.com/google/uuid.UUID" into its import path ("github.com/google/uuid")
+// and the selector to use at the point of reference ("uuid.UUID"). A bare
+// type name such as "string" has no import path and is returned as-is.
+func splitQualifiedType(t string) (importPath, selector string) {
+	idx := strings.LastIndex(t, ".")
+	slash := strings.LastIndex(t, "/")
+	if idx == -1 || idx < slash {
+		return "", t
+	}
+	importPath = t[:idx]
+	pkgAlias := importPath[strings.LastIndex(importPath, "/")+1:]
+	return importPath, pkgAlias + "." + t[idx+1:]
+}
+
+// resolveRef resolves a local "#/definitions/Foo" or "#/$defs/Foo" pointer
+// to a named type, generating it on first use and reusing it (by ref path)
+// thereafter.
+func (g *generator) resolveRef(ref string) (string, error) {
+	if name, ok := g.refs[ref]; ok {
+		return name, nil
+	}
+
+	const defPrefix = "#/definitions/"
+	const defsPrefix = "#/$defs/"
+
+	var key string
+	switch {
+	case strings.HasPrefix(ref, defPrefix):
+		key = strings.TrimPrefix(ref, defPrefix)
+	case strings.HasPrefix(ref, defsPrefix):
+		key = strings.TrimPrefix(ref, defsPrefix)
+	default:
+		return "", fmt.Errorf("unsupported $ref: %s (only local #/definitions/... and #/$defs/... refs are supported)", ref)
+	}
+
+	target, ok := g.root.Definitions[key]
+	if !ok {
+		target, ok = g.root.Defs[key]
+	}
+	if !ok {
+		return "", fmt.Errorf("$ref %s: no such definition", ref)
+	}
+
+	// Reserve the name and record it against ref before recursing into the
+	// definition's body. A self- or mutually-referential schema (e.g. a
+	// tree node whose child is itself a "#/definitions/Node") re-enters
+	// resolveRef for the same ref while declareNamed is still building that
+	// body; without the mapping in place already, that re-entry would
+	// recurse forever instead of returning the name.
+	name := g.reserveName(key)
+	g.refs[ref] = name
+
+	if _, err := g.declareNamed(name, target); err != nil {
+		delete(g.refs, ref)
+		return "", err
+	}
+	return name, nil
+}
+
+// enumDecl renders a named scalar type plus its typed constants for a
+// schema with an "enum" keyword.
+func (g *generator) enumDecl(name string, s *Schema) (string, error) {
+	base := "string"
+	switch {
+	case s.hasType("integer"):
+		base = "int64"
+	case s.hasType("number"):
+		base = "float64"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "type %s %s\n\nconst (", name, base)
+	for _, v := range s.Enum {
+		switch val := v.(type) {
+		case string:
+			fmt.Fprintf(&b, "\n%s%s %s = %q", name, exportedName(val), name, val)
+		case float64:
+			if base == "float64" {
+				fmt.Fprintf(&b, "\n%s%s %s = %s", name, floatConstSuffix(val), name, strconv.FormatFloat(val, 'f', -1, 64))
+			} else {
+				fmt.Fprintf(&b, "\n%s%v %s = %v", name, int64(val), name, int64(val))
+			}
+		default:
+			return "", fmt.Errorf("enum value %v: unsupported type %T", v, v)
+		}
+	}
+	b.WriteString("\n)")
+	return b.String(), nil
+}
+
+// floatConstSuffix turns a float64 enum value into an identifier-safe
+// suffix for its generated constant name, e.g. 1.5 -> "1_5", -2.5 ->
+// "Neg2_5".
+func floatConstSuffix(v float64) string {
+	s := strconv.FormatFloat(v, 'f', -1, 64)
+	s = strings.ReplaceAll(s, "-", "Neg")
+	s = strings.ReplaceAll(s, ".", "_")
+	return s
+}
+
+// unionDecl renders a marker interface for a oneOf/anyOf schema plus one
+// concrete struct type per variant (a $ref variant is declared over the
+// referenced definition's type). If any variant isn't an object schema,
+// it falls back to json.RawMessage instead, since there is no idiomatic Go
+// shape to lift non-object variants into.
+func (g *generator) unionDecl(name string, s *Schema) (string, error) {
+	variants := s.OneOf
+	if len(variants) == 0 {
+		variants = s.AnyOf
+	}
+
+	for _, v := range variants {
+		if v.Ref == "" && !v.hasType("object") && v.Properties == nil {
+			g.imports["encoding/json"] = true
+			g.declare(name, fmt.Sprintf("type %s = json.RawMessage", name))
+			return name, nil
+		}
+	}
+
+	method := "is" + name
+	g.declare(name, fmt.Sprintf("// %s is implemented by every variant of this union.\ntype %s interface {\n\t%s()\n}", name, name, method))
+
+	for i, v := range variants {
+		variantName := name
+		if v.Title != "" {
+			variantName = exportedName(v.Title)
+		} else {
+			variantName = fmt.Sprintf("%s%d", name, i+1)
+		}
+		var body string
+		var err error
+		if v.Ref != "" {
+			body, err = g.resolveRef(v.Ref)
+		} else {
+			body, err = g.structBody(variantName, v)
+		}
+		if err != nil {
+			return "", err
+		}
+		decl := fmt.Sprintf("type %s %s\n\nfunc (%s) %s() {}", variantName, body, variantName, method)
+		g.declare(variantName, decl)
+	}
+
+	return name, nil
+}
+
+// exportedName title-cases s, drops "_", "-" and " " separators, replaces
+// other characters that aren't valid in a Go identifier with "_", and
+// prefixes "X" when the result doesn't start with a letter, loosely
+// mirroring the main package's fmtFieldName.
+func exportedName(s string) string {
+	parts := strings.FieldsFunc(s, func(r rune) bool {
+		return r == '_' || r == '-' || r == ' '
+	})
+	for i := range parts {
+		parts[i] = strings.Title(parts[i])
+	}
+	joined := strings.Join(parts, "")
+	if joined == "" {
+		return "X"
+	}
+	runes := []rune(joined)
+	for i, c := range runes {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+			runes[i] = '_'
+		}
+	}
+	if !unicode.IsLetter(runes[0]) {
+		return "X" + string(runes)
+	}
+	return string(runes)
+}
+
+// singularize makes a best-effort attempt to turn a plural field name (used
+// to name an array) into a singular one, for naming the element type.
+func singularize(s string) string {
+	switch {
+	case strings.HasSuffix(s, "ies"):
+		return s[:len(s)-3] + "y"
+	case strings.HasSuffix(s, "ses"):
+		return s[:len(s)-2]
+	case strings.HasSuffix(s, "s") && !strings.HasSuffix(s, "ss"):
+		return s[:len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,199 @@
+package schema
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// collapseSpace normalizes runs of horizontal whitespace to a single space,
+// so assertions against generated source don't depend on gofmt's column
+// alignment within declarations like const blocks.
+var runsOfSpace = regexp.MustCompile(`[ \t]+`)
+
+func collapseSpace(s string) string {
+	return runsOfSpace.ReplaceAllString(s, " ")
+}
+
+func TestGenerateSelfReferentialRef(t *testing.T) {
+	doc := `{
+		"$ref": "#/definitions/Node",
+		"definitions": {
+			"Node": {
+				"type": "object",
+				"properties": {
+					"name": {"type": "string"},
+					"child": {"$ref": "#/definitions/Node"}
+				},
+				"required": ["name"]
+			}
+		}
+	}`
+
+	out, err := Generate(strings.NewReader(doc), "Node", "gen", Options{})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	src := string(out)
+	if !strings.Contains(src, "Child *Node") {
+		t.Errorf("expected a pointer-typed self-reference (Child *Node), got:\n%s", src)
+	}
+}
+
+func TestGenerateOptionalObjectFieldIsPointer(t *testing.T) {
+	doc := `{
+		"title": "Foo",
+		"type": "object",
+		"properties": {
+			"name": {"type": "string"},
+			"meta": {
+				"type": "object",
+				"properties": {
+					"tag": {"type": "string"}
+				}
+			}
+		},
+		"required": ["name"]
+	}`
+
+	out, err := Generate(strings.NewReader(doc), "Foo", "gen", Options{})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	src := string(out)
+	if !strings.Contains(src, "Meta *Meta") {
+		t.Errorf("expected Meta to be a pointer-typed field (so omitempty isn't a no-op), got:\n%s", src)
+	}
+}
+
+func TestGenerateRequiredSelfReferentialRef(t *testing.T) {
+	doc := `{
+		"$ref": "#/definitions/Node",
+		"definitions": {
+			"Node": {
+				"type": "object",
+				"properties": {
+					"name": {"type": "string"},
+					"child": {"$ref": "#/definitions/Node"}
+				},
+				"required": ["name", "child"]
+			}
+		}
+	}`
+
+	out, err := Generate(strings.NewReader(doc), "Node", "gen", Options{})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	src := string(out)
+	if !strings.Contains(src, "Child *Node") {
+		t.Errorf("expected a pointer-typed self-reference even when required (Child *Node), got:\n%s", src)
+	}
+}
+
+func TestGenerateRootRef(t *testing.T) {
+	doc := `{
+		"$ref": "#/definitions/Person",
+		"definitions": {
+			"Person": {
+				"type": "object",
+				"properties": {
+					"name": {"type": "string"}
+				},
+				"required": ["name"]
+			}
+		}
+	}`
+
+	out, err := Generate(strings.NewReader(doc), "Root", "gen", Options{})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	src := string(out)
+	if !strings.Contains(src, "type Person struct") {
+		t.Errorf("expected the referenced definition Person to be declared, got:\n%s", src)
+	}
+	if !strings.Contains(src, "type Root = Person") {
+		t.Errorf("expected the root name to alias the resolved $ref, got:\n%s", src)
+	}
+}
+
+func TestGenerateStringEnum(t *testing.T) {
+	doc := `{
+		"title": "Color",
+		"type": "string",
+		"enum": ["red", "green", "blue"]
+	}`
+
+	out, err := Generate(strings.NewReader(doc), "Color", "gen", Options{})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	src := collapseSpace(string(out))
+	for _, want := range []string{"type Color string", `ColorRed Color = "red"`} {
+		if !strings.Contains(src, want) {
+			t.Errorf("expected %q in output, got:\n%s", want, src)
+		}
+	}
+}
+
+func TestGenerateNumberEnumKeepsPrecision(t *testing.T) {
+	doc := `{
+		"title": "Level",
+		"type": "number",
+		"enum": [1.5, -2.5, 3]
+	}`
+
+	out, err := Generate(strings.NewReader(doc), "Level", "gen", Options{})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	src := collapseSpace(string(out))
+	for _, want := range []string{"type Level float64", "Level1_5 Level = 1.5", "LevelNeg2_5 Level = -2.5"} {
+		if !strings.Contains(src, want) {
+			t.Errorf("expected %q in output, got:\n%s", want, src)
+		}
+	}
+}
+
+func TestValidateTagDropsCommaPattern(t *testing.T) {
+	s := &Schema{Pattern: "^[A-Z]{2,4}$"}
+	tag := validateTag(s, false)
+	if strings.Contains(tag, "regexp=") {
+		t.Errorf("expected a comma-bearing pattern to be dropped from the tag, got %q", tag)
+	}
+}
+
+func TestValidateTagKeepsSafePattern(t *testing.T) {
+	s := &Schema{Pattern: "^[a-z]+$"}
+	tag := validateTag(s, false)
+	if tag != "regexp=^[a-z]+$" {
+		t.Errorf("got %q, want regexp=^[a-z]+$", tag)
+	}
+}
+
+func TestGenerateIntegerEnumUnaffected(t *testing.T) {
+	doc := `{
+		"title": "Priority",
+		"type": "integer",
+		"enum": [1, 2, 3]
+	}`
+
+	out, err := Generate(strings.NewReader(doc), "Priority", "gen", Options{})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	src := collapseSpace(string(out))
+	for _, want := range []string{"type Priority int64", "Priority1 Priority = 1"} {
+		if !strings.Contains(src, want) {
+			t.Errorf("expected %q in output, got:\n%s", want, src)
+		}
+	}
+}